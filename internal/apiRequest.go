@@ -1,6 +1,7 @@
 package internal
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -14,11 +15,22 @@ import (
 
 type mongoProjectID struct {
 	Results []struct {
-		ID       string `json:"id"`
-		TypeName string `json:"typeName"`
+		ID             string `json:"id"`
+		TypeName       string `json:"typeName"`
+		ReplicaSetName string `json:"replicaSetName"`
+		ShardName      string `json:"shardName"`
 	} `json:"results"`
 }
 
+// Process identifies a single mongod/mongos node in the project, i.e. one
+// entry returned by the Atlas /processes endpoint.
+type Process struct {
+	ID             string `json:"processId"`
+	TypeName       string `json:"typeName"`
+	ReplicaSetName string `json:"replicaSetName"`
+	ShardName      string `json:"shardName"`
+}
+
 type slowQueries struct {
 	SlowQueries []struct {
 		Line      string `json:"line"`
@@ -62,84 +74,159 @@ type suggestedIndexes struct {
 	} `json:"suggestedIndexes"`
 }
 
-//GetData retrieves the data from AtlasDB and sends them to stdout.
-func GetData(groupID string, publicKey string, privateKey string, since int) {
-
-	time := time.Now().Add(time.Duration(-since)*time.Hour).UnixNano() / 1000000
-
-	primary, err := getPrimary(groupID, publicKey, privateKey)
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	connectionString := fmt.Sprintf("https://cloud.mongodb.com/api/atlas/v1.0/groups/%s/processes/%s/performanceAdvisor/", groupID, primary)
-
-	getSlowQueries(connectionString, publicKey, privateKey, time)
-	getSuggestedIndexes(connectionString, publicKey, privateKey, time)
-}
-
-func getPrimary(groupID string, publicKey string, privateKey string) (string, error) {
+// getProcesses returns every process (mongod/mongos) in the project.
+func getProcesses(groupID string, publicKey string, privateKey string) ([]Process, error) {
 
 	request := fmt.Sprintf("https://cloud.mongodb.com/api/atlas/v1.0/groups/%s/processes/", groupID)
 
-	resp, err := doRequest(request, publicKey, privateKey)
+	resp, err := instrumentRequest("processes", func() ([]byte, error) {
+		return withRetry(func() ([]byte, error) {
+			return doRequest(request, publicKey, privateKey)
+		})
+	})
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	var responses mongoProjectID
-	err = json.Unmarshal(resp, &responses)
-	if err != nil {
-		return "", err
+	if err := json.Unmarshal(resp, &responses); err != nil {
+		return nil, err
 	}
 
+	processes := make([]Process, 0, len(responses.Results))
 	for _, response := range responses.Results {
-		if strings.Contains(strings.ToLower(response.TypeName), "primary") {
-			log.Debug("Primary database found: ", response.ID)
-			return response.ID, nil
+		processes = append(processes, Process{
+			ID:             response.ID,
+			TypeName:       response.TypeName,
+			ReplicaSetName: response.ReplicaSetName,
+			ShardName:      response.ShardName,
+		})
+	}
+	return processes, nil
+}
+
+// filterProcesses narrows processes down to the ones matching the given
+// criteria. includeSecondaries, when false, restricts the result to
+// primaries (the historical default). replicaSet/shard, when non-empty,
+// restrict the result to that replica set or shard.
+func filterProcesses(processes []Process, includeSecondaries bool, replicaSet string, shard string) []Process {
+	var filtered []Process
+	for _, process := range processes {
+		if !includeSecondaries && !strings.Contains(strings.ToLower(process.TypeName), "primary") {
+			continue
 		}
+		if replicaSet != "" && process.ReplicaSetName != replicaSet {
+			continue
+		}
+		if shard != "" && process.ShardName != shard {
+			continue
+		}
+		filtered = append(filtered, process)
 	}
-	return "", fmt.Errorf("No Primary Database found")
+	return filtered
 }
 
-func getSlowQueries(connection string, publicKey string, privateKey string, time int64) {
+// getSlowQueries fetches the slow-query log lines since time, emits an event
+// per line (plus one per query shape), and returns the highest parsed
+// timestamp seen per namespace so the caller can advance that namespace's
+// cursor from the log lines themselves, rather than from an unrelated
+// endpoint.
+func getSlowQueries(ctx context.Context, connection string, publicKey string, privateKey string, time int64, process Process, sinks sinkSet) (map[string]int64, error) {
 
 	request := fmt.Sprintf("%sslowQueryLogs?since=%v", connection, time)
 
-	resp, err := doRequest(request, publicKey, privateKey)
+	resp, err := instrumentRequest("slowQueryLogs", func() ([]byte, error) {
+		return withRetry(func() ([]byte, error) {
+			return doRequest(request, publicKey, privateKey)
+		})
+	})
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
 	var responses slowQueries
-	err = json.Unmarshal(resp, &responses)
-	if err != nil {
-		log.Error(err)
+	if err := json.Unmarshal(resp, &responses); err != nil {
+		return nil, err
 	}
 
+	counts := map[[3]string]int{}
+	shapes := map[string][]parsedSlowQuery{}
+	shapeNamespace := map[string]string{}
+	namespaceMaxTs := map[string]int64{}
+
 	for _, response := range responses.SlowQueries {
 		namespace := strings.Split(response.Namespace, ".")
-		log.WithFields(log.Fields{
-			"line":       response.Line,
-			"database":   namespace[0],
-			"collection": namespace[1],
-		}).Info("Slow Query found")
+		parsed := parseSlowQueryLine(response.Line)
+
+		if parsed.Ts > namespaceMaxTs[response.Namespace] {
+			namespaceMaxTs[response.Namespace] = parsed.Ts
+		}
+
+		sinks.emit(ctx, Event{
+			Type:    EventTypeSlowQuery,
+			Process: process,
+			SlowQuery: &SlowQueryEvent{
+				Line:           response.Line,
+				Namespace:      response.Namespace,
+				Database:       namespace[0],
+				Collection:     namespace[1],
+				Op:             parsed.Op,
+				PlanSummary:    parsed.PlanSummary,
+				DocsExamined:   parsed.DocsExamined,
+				KeysExamined:   parsed.KeysExamined,
+				NReturned:      parsed.NReturned,
+				NModified:      parsed.NModified,
+				DurationMillis: parsed.DurationMillis,
+				QueryHash:      parsed.QueryHash,
+				PlanCacheKey:   parsed.PlanCacheKey,
+				Predicate:      parsed.Predicate,
+			},
+		})
+
+		counts[[3]string{namespace[0], namespace[1], response.Namespace}]++
+
+		if parsed.QueryHash != "" {
+			shapes[parsed.QueryHash] = append(shapes[parsed.QueryHash], parsed)
+			shapeNamespace[parsed.QueryHash] = response.Namespace
+		}
 	}
+
+	for key, count := range counts {
+		slowQueryCount.WithLabelValues(key[0], key[1], key[2], process.ID).Set(float64(count))
+	}
+
+	for queryHash, queries := range shapes {
+		sinks.emit(ctx, Event{
+			Type:           EventTypeSlowQueryShape,
+			Process:        process,
+			SlowQueryShape: summarizeShape(queryHash, shapeNamespace[queryHash], queries),
+		})
+	}
+
+	return namespaceMaxTs, nil
 }
 
-func getSuggestedIndexes(connection string, publicKey string, privateKey string, time int64) {
+func getSuggestedIndexes(ctx context.Context, connection string, publicKey string, privateKey string, time int64, process Process, sinks sinkSet) (*suggestedIndexes, error) {
 
 	request := fmt.Sprintf("%ssuggestedIndexes?since=%v", connection, time)
 
-	resp, err := doRequest(request, publicKey, privateKey)
+	resp, err := instrumentRequest("suggestedIndexes", func() ([]byte, error) {
+		return withRetry(func() ([]byte, error) {
+			return doRequest(request, publicKey, privateKey)
+		})
+	})
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
 	var responses suggestedIndexes
-	err = json.Unmarshal(resp, &responses)
-	if err != nil {
-		log.Error(err)
+	if err := json.Unmarshal(resp, &responses); err != nil {
+		return nil, err
+	}
+
+	for _, shape := range responses.Shapes {
+		queryShapeAvgMs.WithLabelValues(shape.ID, shape.Namespace, process.ID).Set(float64(shape.AvgMs))
+		queryShapeInefficiencyScore.WithLabelValues(shape.ID, shape.Namespace, process.ID).Set(float64(shape.InefficiencyScore))
 	}
 
 	for _, response := range responses.SuggestedIndexes {
@@ -155,26 +242,36 @@ func getSuggestedIndexes(connection string, publicKey string, privateKey string,
 			indexes += string(i)
 		}
 
+		suggestedIndexWeight.WithLabelValues(namespace[0], namespace[1], response.ID, process.ID).Set(response.Weight)
+
 		for _, impact := range response.Impact {
 			for _, shape := range responses.Shapes {
 				if impact == shape.ID {
-					log.WithFields(log.Fields{
-						"id":                response.ID,
-						"impact":            impact,
-						"index":             indexes,
-						"database":          namespace[0],
-						"collection":        namespace[1],
-						"weight":            response.Weight,
-						"avgMs":             shape.AvgMs,
-						"count":             shape.Count,
-						"inefficiencyScore": shape.InefficiencyScore,
-					}).Info("Suggested index found.")
+					sinks.emit(ctx, Event{
+						Type:    EventTypeSuggestedIndex,
+						Process: process,
+						SuggestedIndex: &SuggestedIndexEvent{
+							ID:                response.ID,
+							Impact:            impact,
+							Index:             indexes,
+							Database:          namespace[0],
+							Collection:        namespace[1],
+							Weight:            response.Weight,
+							AvgMs:             shape.AvgMs,
+							Count:             shape.Count,
+							InefficiencyScore: shape.InefficiencyScore,
+						},
+					})
 				}
 			}
 		}
 	}
+
+	return &responses, nil
 }
 
+// doRequest performs a single attempt of a digest-authenticated GET request.
+// Retries (if any) are handled by the caller via withRetry.
 func doRequest(uri string, publicKey string, privateKey string) ([]byte, error) {
 
 	t := dac.NewTransport(publicKey, privateKey)
@@ -203,5 +300,12 @@ func doRequest(uri string, publicKey string, privateKey string) ([]byte, error)
 		return nil, err
 	}
 
+	if isRetryableStatus(resp.StatusCode) {
+		return nil, &httpStatusError{statusCode: resp.StatusCode, retryAfter: parseRetryAfter(resp.Header.Get("Retry-After"))}
+	}
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("atlas API returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+
 	return respBody, nil
 }
@@ -0,0 +1,211 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	log "github.com/sirupsen/logrus"
+)
+
+// defaultConcurrency bounds how many processes are fanned out to at once
+// when Config.Concurrency is unset.
+const defaultConcurrency = 5
+
+// Config holds everything needed to talk to the Atlas Performance Advisor and
+// to run the Prometheus exporter.
+type Config struct {
+	GroupID    string
+	PublicKey  string
+	PrivateKey string
+	Since      int
+	Listen     string
+
+	IncludeSecondaries bool
+	ReplicaSet         string
+	Shard              string
+	Concurrency        int
+
+	PollInterval time.Duration
+	StateFile    string
+
+	// Sinks receive every SlowQueryEvent/SuggestedIndexEvent. If empty, a
+	// StdoutSink is used, preserving the tool's original behavior.
+	Sinks []Sink
+}
+
+// sinkSet returns cfg.Sinks, or a StdoutSink if none were configured.
+func (cfg Config) sinkSet() sinkSet {
+	if len(cfg.Sinks) == 0 {
+		return sinkSet{NewStdoutSink()}
+	}
+	return sinkSet(cfg.Sinks)
+}
+
+// Collector polls the Atlas Performance Advisor API and keeps the exported
+// Prometheus metrics up to date.
+type Collector struct {
+	cfg Config
+}
+
+// NewCollector builds a Collector for the given Config.
+func NewCollector(cfg Config) *Collector {
+	return &Collector{cfg: cfg}
+}
+
+// collect fetches the selected processes, then fans out slow-query and
+// suggested-index collection across them with a bounded worker pool,
+// updating the package metrics. When store is non-nil, the `since` used for
+// each process's Atlas queries is taken from that process's cursor instead
+// of cfg.Since, and the cursor is advanced from the response.
+func (c *Collector) collect(ctx context.Context, store *cursorStore) error {
+	processes, err := getProcesses(c.cfg.GroupID, c.cfg.PublicKey, c.cfg.PrivateKey)
+	if err != nil {
+		return err
+	}
+
+	selected := filterProcesses(processes, c.cfg.IncludeSecondaries, c.cfg.ReplicaSet, c.cfg.Shard)
+	if len(selected) == 0 {
+		return fmt.Errorf("no processes matched the configured filters")
+	}
+
+	// Clear last cycle's label combinations so a namespace/index that's gone
+	// away doesn't keep reporting a stale value.
+	resetGauges()
+
+	concurrency := c.cfg.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultConcurrency
+	}
+
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+
+	for _, process := range selected {
+		process := process
+		wg.Add(1)
+		sem <- struct{}{}
+
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			if err := c.collectProcess(ctx, process, store); err != nil {
+				log.WithField("processId", process.ID).Error(err)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	// Flush any sinks that batch events (e.g. ElasticsearchSink) so a
+	// collection pass producing fewer events than a full batch still gets
+	// delivered.
+	c.cfg.sinkSet().flush(ctx)
+
+	return nil
+}
+
+func (c *Collector) collectProcess(ctx context.Context, process Process, store *cursorStore) error {
+	connectionString := fmt.Sprintf("https://cloud.mongodb.com/api/atlas/v1.0/groups/%s/processes/%s/performanceAdvisor/", c.cfg.GroupID, process.ID)
+	fallbackSince := time.Now().Add(time.Duration(-c.cfg.Since)*time.Hour).UnixNano() / 1000000
+
+	since := fallbackSince
+	if store != nil {
+		since = store.processSince(process.ID, fallbackSince)
+	}
+
+	sinks := c.cfg.sinkSet()
+
+	namespaceMaxTs, err := getSlowQueries(ctx, connectionString, c.cfg.PublicKey, c.cfg.PrivateKey, since, process, sinks)
+	if err != nil {
+		log.Error(err)
+	}
+	if store != nil {
+		for namespace, ts := range namespaceMaxTs {
+			store.advance(process.ID, namespace, ts)
+		}
+	}
+
+	shapes, err := getSuggestedIndexes(ctx, connectionString, c.cfg.PublicKey, c.cfg.PrivateKey, since, process, sinks)
+	if err != nil {
+		log.Error(err)
+		return nil
+	}
+
+	if store != nil {
+		for _, shape := range shapes.Shapes {
+			var namespaceMax int64
+			for _, op := range shape.Operations {
+				if op.Stats.Ts > namespaceMax {
+					namespaceMax = op.Stats.Ts
+				}
+			}
+			store.advance(process.ID, shape.Namespace, namespaceMax)
+		}
+	}
+
+	return nil
+}
+
+// Run operates as a daemon: every cfg.PollInterval it re-queries Atlas for
+// slow queries and suggested indexes across the selected processes,
+// advancing `since` from a cursor (persisted at cfg.StateFile) rather than
+// always from now-<Since hours>, so a restart doesn't re-flood downstream
+// consumers with records already seen.
+func Run(ctx context.Context, cfg Config) error {
+	store, err := loadCursorStore(cfg.StateFile)
+	if err != nil {
+		return err
+	}
+
+	c := NewCollector(cfg)
+	ticker := time.NewTicker(cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := c.collect(ctx, store); err != nil {
+			log.Error(err)
+		}
+		if err := store.save(); err != nil {
+			log.Error(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			cfg.sinkSet().flush(context.Background())
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Serve runs the collector as a long-lived process: it polls Atlas every
+// cfg.PollInterval and exposes the resulting metrics on cfg.Listen at
+// /metrics.
+func (c *Collector) Serve(ctx context.Context) error {
+	go func() {
+		if err := Run(ctx, c.cfg); err != nil {
+			log.Error(err)
+		}
+	}()
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+
+	server := &http.Server{Addr: c.cfg.Listen, Handler: mux}
+	log.Info("Listening on ", c.cfg.Listen)
+	return server.ListenAndServe()
+}
+
+// GetData retrieves the data from AtlasDB once, publishing it to cfg's
+// configured sinks (stdout by default).
+func GetData(cfg Config) {
+	c := NewCollector(cfg)
+	if err := c.collect(context.Background(), nil); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,103 @@
+package internal
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sync"
+)
+
+// cursorStore persists the highest observed timestamp per (process,
+// namespace) - taken from each slow-query line's own timestamp, and from
+// suggestedIndexes shape stats where available - so that a restart of the
+// daemon doesn't re-emit records Atlas has already reported in a prior
+// cycle.
+type cursorStore struct {
+	path string
+
+	mu   sync.Mutex
+	data map[string]map[string]int64 // processID -> namespace -> highest ts (ms)
+}
+
+// loadCursorStore reads the cursor state from path, if it exists. A missing
+// file is not an error: it just means this is the first run.
+func loadCursorStore(path string) (*cursorStore, error) {
+	s := &cursorStore{path: path, data: map[string]map[string]int64{}}
+	if path == "" {
+		return s, nil
+	}
+
+	raw, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(raw, &s.data); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// processSince returns the `since` to use for processID's next Atlas query:
+// the minimum cursor recorded across all of its namespaces, or fallback if
+// nothing has been recorded yet. Taking the minimum (rather than, say, the
+// last-written namespace) keeps a namespace that hasn't produced a newer
+// suggested-index shape from being skipped just because a busier namespace
+// on the same process advanced further.
+func (s *cursorStore) processSince(processID string, fallback int64) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	byNamespace, ok := s.data[processID]
+	if !ok || len(byNamespace) == 0 {
+		return fallback
+	}
+
+	min := int64(-1)
+	for _, ts := range byNamespace {
+		if min == -1 || ts < min {
+			min = ts
+		}
+	}
+	return min
+}
+
+// advance records ts for (processID, namespace) if it's higher than what's
+// currently stored.
+func (s *cursorStore) advance(processID, namespace string, ts int64) {
+	if ts <= 0 {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	byNamespace, ok := s.data[processID]
+	if !ok {
+		byNamespace = map[string]int64{}
+		s.data[processID] = byNamespace
+	}
+	if ts > byNamespace[namespace] {
+		byNamespace[namespace] = ts
+	}
+}
+
+// save persists the cursor state to disk. It is a no-op if no path was
+// configured.
+func (s *cursorStore) save() error {
+	if s.path == "" {
+		return nil
+	}
+
+	s.mu.Lock()
+	raw, err := json.Marshal(s.data)
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(s.path, raw, 0644)
+}
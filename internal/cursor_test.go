@@ -0,0 +1,48 @@
+package internal
+
+import "testing"
+
+func TestCursorStoreProcessSince(t *testing.T) {
+	t.Run("falls back when nothing recorded", func(t *testing.T) {
+		s := &cursorStore{data: map[string]map[string]int64{}}
+		if got := s.processSince("p1", 42); got != 42 {
+			t.Fatalf("expected fallback 42, got %d", got)
+		}
+	})
+
+	t.Run("a busy namespace does not skip a quiet one", func(t *testing.T) {
+		s := &cursorStore{data: map[string]map[string]int64{}}
+		s.advance("p1", "db.busy", 1000)
+		s.advance("p1", "db.quiet", 100)
+
+		if got := s.processSince("p1", 0); got != 100 {
+			t.Fatalf("expected since to stay at the quiet namespace's cursor 100, got %d", got)
+		}
+	})
+
+	t.Run("advances once every namespace catches up", func(t *testing.T) {
+		s := &cursorStore{data: map[string]map[string]int64{}}
+		s.advance("p1", "db.busy", 1000)
+		s.advance("p1", "db.quiet", 100)
+		s.advance("p1", "db.quiet", 900)
+
+		if got := s.processSince("p1", 0); got != 900 {
+			t.Fatalf("expected since to advance to 900, got %d", got)
+		}
+	})
+}
+
+func TestCursorStoreAdvance(t *testing.T) {
+	s := &cursorStore{data: map[string]map[string]int64{}}
+
+	s.advance("p1", "db.a", 100)
+	s.advance("p1", "db.a", 50) // lower value must not regress the cursor
+	if got := s.data["p1"]["db.a"]; got != 100 {
+		t.Fatalf("expected cursor to stay at 100, got %d", got)
+	}
+
+	s.advance("p1", "db.a", 0) // non-positive values are ignored
+	if got := s.data["p1"]["db.a"]; got != 100 {
+		t.Fatalf("expected cursor to stay at 100, got %d", got)
+	}
+}
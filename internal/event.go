@@ -0,0 +1,69 @@
+package internal
+
+// EventType distinguishes the kinds of events a Sink can receive.
+type EventType string
+
+const (
+	// EventTypeSlowQuery is emitted for every slow query log line Atlas reports.
+	EventTypeSlowQuery EventType = "slow_query"
+	// EventTypeSuggestedIndex is emitted for every suggested index Atlas reports.
+	EventTypeSuggestedIndex EventType = "suggested_index"
+	// EventTypeSlowQueryShape is emitted once per queryHash per process per
+	// polling cycle, aggregating the slow queries sharing that shape.
+	EventTypeSlowQueryShape EventType = "slow_query_shape"
+)
+
+// Event is the payload handed to a Sink. Exactly one of SlowQuery,
+// SuggestedIndex or SlowQueryShape is set, depending on Type.
+type Event struct {
+	Type           EventType            `json:"type"`
+	Process        Process              `json:"process"`
+	SlowQuery      *SlowQueryEvent      `json:"slowQuery,omitempty"`
+	SuggestedIndex *SuggestedIndexEvent `json:"suggestedIndex,omitempty"`
+	SlowQueryShape *SlowQueryShapeEvent `json:"slowQueryShape,omitempty"`
+}
+
+// SlowQueryEvent describes a single slow query log line, with the raw line
+// alongside the fields parsed out of it by parseSlowQueryLine.
+type SlowQueryEvent struct {
+	Line       string `json:"line"`
+	Namespace  string `json:"namespace"`
+	Database   string `json:"database"`
+	Collection string `json:"collection"`
+
+	Op             string `json:"op,omitempty"`
+	PlanSummary    string `json:"planSummary,omitempty"`
+	DocsExamined   int    `json:"docsExamined,omitempty"`
+	KeysExamined   int    `json:"keysExamined,omitempty"`
+	NReturned      int    `json:"nreturned,omitempty"`
+	NModified      int    `json:"nModified,omitempty"`
+	DurationMillis int    `json:"durationMillis,omitempty"`
+	QueryHash      string `json:"queryHash,omitempty"`
+	PlanCacheKey   string `json:"planCacheKey,omitempty"`
+	Predicate      string `json:"predicate,omitempty"`
+}
+
+// SlowQueryShapeEvent aggregates every slow query sharing a queryHash on a
+// single process within a single polling cycle.
+type SlowQueryShapeEvent struct {
+	QueryHash                  string  `json:"queryHash"`
+	Namespace                  string  `json:"namespace"`
+	Count                      int     `json:"count"`
+	P50DurationMillis          int     `json:"p50DurationMillis"`
+	P95DurationMillis          int     `json:"p95DurationMillis"`
+	AvgDocsExaminedPerReturned float64 `json:"avgDocsExaminedPerReturned"`
+}
+
+// SuggestedIndexEvent describes a single suggested index, merged with the
+// query shape it addresses.
+type SuggestedIndexEvent struct {
+	ID                string  `json:"id"`
+	Impact            string  `json:"impact"`
+	Index             string  `json:"index"`
+	Database          string  `json:"database"`
+	Collection        string  `json:"collection"`
+	Weight            float64 `json:"weight"`
+	AvgMs             int     `json:"avgMs"`
+	Count             int     `json:"count"`
+	InefficiencyScore int     `json:"inefficiencyScore"`
+}
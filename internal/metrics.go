@@ -0,0 +1,74 @@
+package internal
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	slowQueryCount = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "atlas_slow_query_count",
+		Help: "Number of slow queries observed for a namespace in the current polling cycle.",
+	}, []string{"database", "collection", "namespace", "process"})
+
+	suggestedIndexWeight = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "atlas_suggested_index_weight",
+		Help: "Weight Atlas assigns to a suggested index.",
+	}, []string{"database", "collection", "index_id", "process"})
+
+	queryShapeAvgMs = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "atlas_query_shape_avg_ms",
+		Help: "Average duration in milliseconds of a query shape, as reported by the performance advisor.",
+	}, []string{"shape_id", "namespace", "process"})
+
+	queryShapeInefficiencyScore = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "atlas_query_shape_inefficiency_score",
+		Help: "Inefficiency score Atlas assigns to a query shape.",
+	}, []string{"shape_id", "namespace", "process"})
+
+	apiRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "atlas_api_request_duration_seconds",
+		Help: "Duration of calls made to the Atlas Administration API.",
+	}, []string{"endpoint"})
+
+	apiRequestErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "atlas_api_request_errors_total",
+		Help: "Number of calls made to the Atlas Administration API that returned an error.",
+	}, []string{"endpoint"})
+)
+
+func init() {
+	prometheus.MustRegister(
+		slowQueryCount,
+		suggestedIndexWeight,
+		queryShapeAvgMs,
+		queryShapeInefficiencyScore,
+		apiRequestDuration,
+		apiRequestErrors,
+	)
+}
+
+// resetGauges clears every label combination previously set on the
+// per-cycle gauges. Without this, a namespace going quiet or a suggested
+// index being resolved would leave its last value reported forever, since
+// collect only ever Sets the combinations seen in the current poll.
+func resetGauges() {
+	slowQueryCount.Reset()
+	suggestedIndexWeight.Reset()
+	queryShapeAvgMs.Reset()
+	queryShapeInefficiencyScore.Reset()
+}
+
+// instrumentRequest runs fn, recording its duration and, on failure, incrementing
+// the error counter for endpoint. It is used to wrap the raw calls made by
+// getProcesses, getSlowQueries and getSuggestedIndexes.
+func instrumentRequest(endpoint string, fn func() ([]byte, error)) ([]byte, error) {
+	start := time.Now()
+	resp, err := fn()
+	apiRequestDuration.WithLabelValues(endpoint).Observe(time.Since(start).Seconds())
+	if err != nil {
+		apiRequestErrors.WithLabelValues(endpoint).Inc()
+	}
+	return resp, err
+}
@@ -0,0 +1,39 @@
+package internal
+
+import "testing"
+
+func TestFilterProcesses(t *testing.T) {
+	processes := []Process{
+		{ID: "p1", TypeName: "PRIMARY", ReplicaSetName: "rs0", ShardName: "shard0"},
+		{ID: "p2", TypeName: "SECONDARY", ReplicaSetName: "rs0", ShardName: "shard0"},
+		{ID: "p3", TypeName: "PRIMARY", ReplicaSetName: "rs1", ShardName: "shard1"},
+	}
+
+	t.Run("primary only by default", func(t *testing.T) {
+		got := filterProcesses(processes, false, "", "")
+		if len(got) != 2 {
+			t.Fatalf("expected 2 primaries, got %d", len(got))
+		}
+	})
+
+	t.Run("include secondaries", func(t *testing.T) {
+		got := filterProcesses(processes, true, "", "")
+		if len(got) != 3 {
+			t.Fatalf("expected all 3 processes, got %d", len(got))
+		}
+	})
+
+	t.Run("filters by replica set", func(t *testing.T) {
+		got := filterProcesses(processes, true, "rs0", "")
+		if len(got) != 2 {
+			t.Fatalf("expected 2 processes in rs0, got %d", len(got))
+		}
+	})
+
+	t.Run("filters by shard", func(t *testing.T) {
+		got := filterProcesses(processes, true, "", "shard1")
+		if len(got) != 1 || got[0].ID != "p3" {
+			t.Fatalf("expected only p3, got %+v", got)
+		}
+	})
+}
@@ -0,0 +1,155 @@
+package internal
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	opRegex             = regexp.MustCompile(`\b(query|getmore|update|remove|command|insert|aggregate|findandmodify)\b`)
+	timestampRegex      = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}\.\d{3}[+-]\d{4})`)
+	planSummaryRegex    = regexp.MustCompile(`planSummary:\s*(\S+(?:\s+\{[^}]*\})?)`)
+	keysExaminedRegex   = regexp.MustCompile(`keysExamined:(\d+)`)
+	docsExaminedRegex   = regexp.MustCompile(`docsExamined:(\d+)`)
+	nReturnedRegex      = regexp.MustCompile(`n[Rr]eturned:(\d+)`)
+	nModifiedRegex      = regexp.MustCompile(`nModified:(\d+)`)
+	durationMillisRegex = regexp.MustCompile(`(\d+)ms\s*$`)
+	queryHashRegex      = regexp.MustCompile(`queryHash:([0-9A-Fa-f]+)`)
+	planCacheKeyRegex   = regexp.MustCompile(`planCacheKey:([0-9A-Fa-f]+)`)
+)
+
+// slowQueryTimestampLayout matches the leading timestamp MongoDB's profiler
+// stamps on every log line, e.g. "2026-07-29T10:00:00.000+0000".
+const slowQueryTimestampLayout = "2006-01-02T15:04:05.000-0700"
+
+// parsedSlowQuery holds the structured fields extracted from a single
+// MongoDB profiler "Slow query { ... }" log line.
+type parsedSlowQuery struct {
+	Ts             int64
+	Op             string
+	PlanSummary    string
+	DocsExamined   int
+	KeysExamined   int
+	NReturned      int
+	NModified      int
+	DurationMillis int
+	QueryHash      string
+	PlanCacheKey   string
+	Predicate      string
+}
+
+// parseSlowQueryLine extracts the standard profiler fields out of a raw
+// slow-query log line. Fields that aren't present in the line are left at
+// their zero value; this is a best-effort parse, not a full profiler log
+// grammar.
+func parseSlowQueryLine(line string) parsedSlowQuery {
+	return parsedSlowQuery{
+		Ts:             parseSlowQueryTimestamp(line),
+		Op:             firstSubmatch(opRegex, line),
+		PlanSummary:    firstSubmatch(planSummaryRegex, line),
+		DocsExamined:   firstIntSubmatch(docsExaminedRegex, line),
+		KeysExamined:   firstIntSubmatch(keysExaminedRegex, line),
+		NReturned:      firstIntSubmatch(nReturnedRegex, line),
+		NModified:      firstIntSubmatch(nModifiedRegex, line),
+		DurationMillis: firstIntSubmatch(durationMillisRegex, line),
+		QueryHash:      firstSubmatch(queryHashRegex, line),
+		PlanCacheKey:   firstSubmatch(planCacheKeyRegex, line),
+		Predicate:      extractPredicate(line),
+	}
+}
+
+// parseSlowQueryTimestamp extracts the leading profiler timestamp from line
+// and returns it as milliseconds since the epoch, or 0 if it can't be
+// parsed. This is what the cursor advances from, since it's the only
+// per-record timestamp a slow-query log line actually carries.
+func parseSlowQueryTimestamp(line string) int64 {
+	raw := firstSubmatch(timestampRegex, line)
+	if raw == "" {
+		return 0
+	}
+	ts, err := time.Parse(slowQueryTimestampLayout, raw)
+	if err != nil {
+		return 0
+	}
+	return ts.UnixNano() / int64(time.Millisecond)
+}
+
+func firstSubmatch(re *regexp.Regexp, line string) string {
+	m := re.FindStringSubmatch(line)
+	if len(m) < 2 {
+		return ""
+	}
+	return m[1]
+}
+
+func firstIntSubmatch(re *regexp.Regexp, line string) int {
+	v, err := strconv.Atoi(firstSubmatch(re, line))
+	if err != nil {
+		return 0
+	}
+	return v
+}
+
+// summarizeShape aggregates every parsed slow query sharing queryHash into a
+// single SlowQueryShapeEvent, computing the p50/p95 duration and the
+// average docsExamined:nreturned ratio for the shape.
+func summarizeShape(queryHash string, namespace string, queries []parsedSlowQuery) *SlowQueryShapeEvent {
+	durations := make([]int, len(queries))
+	var ratioSum float64
+
+	for i, q := range queries {
+		durations[i] = q.DurationMillis
+		if q.NReturned > 0 {
+			ratioSum += float64(q.DocsExamined) / float64(q.NReturned)
+		}
+	}
+	sort.Ints(durations)
+
+	return &SlowQueryShapeEvent{
+		QueryHash:                  queryHash,
+		Namespace:                  namespace,
+		Count:                      len(queries),
+		P50DurationMillis:          percentile(durations, 50),
+		P95DurationMillis:          percentile(durations, 95),
+		AvgDocsExaminedPerReturned: ratioSum / float64(len(queries)),
+	}
+}
+
+// percentile returns the p-th percentile of sorted (ascending), using the
+// nearest-rank method. sorted must be non-empty.
+func percentile(sorted []int, p int) int {
+	idx := (p*len(sorted)+99)/100 - 1
+	if idx < 0 {
+		idx = 0
+	}
+	if idx >= len(sorted) {
+		idx = len(sorted) - 1
+	}
+	return sorted[idx]
+}
+
+// extractPredicate pulls out the first balanced `{ ... }` document in line,
+// which for a command log line is the query/command predicate.
+func extractPredicate(line string) string {
+	start := strings.Index(line, "{")
+	if start == -1 {
+		return ""
+	}
+
+	depth := 0
+	for i, r := range line[start:] {
+		switch r {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return line[start : start+i+1]
+			}
+		}
+	}
+	return ""
+}
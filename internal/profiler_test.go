@@ -0,0 +1,87 @@
+package internal
+
+import "testing"
+
+func TestParseSlowQueryLine(t *testing.T) {
+	line := `2026-07-29T10:00:00.000+0000 I COMMAND [conn42] command mydb.mycoll command: find { find: "mycoll" } planSummary: IXSCAN { a: 1 } keysExamined:10 docsExamined:100 nreturned:5 queryHash:ABCD1234 planCacheKey:DEAD BEEF0000 123ms`
+
+	got := parseSlowQueryLine(line)
+
+	if got.Ts != 1785319200000 {
+		t.Errorf("Ts: expected %d, got %d", 1785319200000, got.Ts)
+	}
+	if got.Op != "command" {
+		t.Errorf("Op: expected %q, got %q", "command", got.Op)
+	}
+	if got.KeysExamined != 10 {
+		t.Errorf("KeysExamined: expected 10, got %d", got.KeysExamined)
+	}
+	if got.DocsExamined != 100 {
+		t.Errorf("DocsExamined: expected 100, got %d", got.DocsExamined)
+	}
+	if got.NReturned != 5 {
+		t.Errorf("NReturned: expected 5, got %d", got.NReturned)
+	}
+	if got.DurationMillis != 123 {
+		t.Errorf("DurationMillis: expected 123, got %d", got.DurationMillis)
+	}
+	if got.QueryHash != "ABCD1234" {
+		t.Errorf("QueryHash: expected %q, got %q", "ABCD1234", got.QueryHash)
+	}
+	if got.Predicate != `{ find: "mycoll" }` {
+		t.Errorf("Predicate: expected %q, got %q", `{ find: "mycoll" }`, got.Predicate)
+	}
+
+	t.Run("missing fields stay at zero value", func(t *testing.T) {
+		got := parseSlowQueryLine("no recognizable fields here")
+		if got.Ts != 0 || got.Op != "" || got.DurationMillis != 0 || got.Predicate != "" {
+			t.Fatalf("expected zero values, got %+v", got)
+		}
+	})
+}
+
+func TestSummarizeShape(t *testing.T) {
+	queries := []parsedSlowQuery{
+		{DurationMillis: 10, DocsExamined: 100, NReturned: 10},
+		{DurationMillis: 20, DocsExamined: 50, NReturned: 10},
+		{DurationMillis: 30, DocsExamined: 10, NReturned: 10},
+	}
+
+	got := summarizeShape("ABCD1234", "mydb.mycoll", queries)
+
+	if got.Count != 3 {
+		t.Errorf("Count: expected 3, got %d", got.Count)
+	}
+	if got.P50DurationMillis != 20 {
+		t.Errorf("P50DurationMillis: expected 20, got %d", got.P50DurationMillis)
+	}
+	if got.P95DurationMillis != 30 {
+		t.Errorf("P95DurationMillis: expected 30, got %d", got.P95DurationMillis)
+	}
+
+	wantRatio := (10.0 + 5.0 + 1.0) / 3.0
+	if got.AvgDocsExaminedPerReturned != wantRatio {
+		t.Errorf("AvgDocsExaminedPerReturned: expected %v, got %v", wantRatio, got.AvgDocsExaminedPerReturned)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	cases := []struct {
+		name   string
+		sorted []int
+		p      int
+		want   int
+	}{
+		{"single value", []int{42}, 95, 42},
+		{"p50 of even set", []int{10, 20, 30, 40}, 50, 20},
+		{"p95 of even set", []int{10, 20, 30, 40}, 95, 40},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := percentile(tc.sorted, tc.p); got != tc.want {
+				t.Fatalf("expected %d, got %d", tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package internal
+
+import (
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// RetryConfig controls how withRetry backs off between attempts. It is
+// populated from the --max-retries/--retry-base-delay/--retry-max-delay
+// flags in main.go.
+type RetryConfig struct {
+	MaxRetries int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+}
+
+// DefaultRetryConfig is used by withRetry until SetRetryConfig is called.
+var DefaultRetryConfig = RetryConfig{
+	MaxRetries: 3,
+	BaseDelay:  500 * time.Millisecond,
+	MaxDelay:   30 * time.Second,
+}
+
+// SetRetryConfig overrides DefaultRetryConfig, e.g. from CLI flags.
+func SetRetryConfig(cfg RetryConfig) {
+	DefaultRetryConfig = cfg
+}
+
+// httpStatusError represents a response with a status code that's worth
+// retrying (429/502/503/504), optionally carrying a server-provided
+// Retry-After delay.
+type httpStatusError struct {
+	statusCode int
+	retryAfter time.Duration
+}
+
+func (e *httpStatusError) Error() string {
+	return "atlas API returned retryable status " + strconv.Itoa(e.statusCode)
+}
+
+func isRetryableStatus(statusCode int) bool {
+	switch statusCode {
+	case 429, 502, 503, 504:
+		return true
+	default:
+		return false
+	}
+}
+
+func parseRetryAfter(header string) time.Duration {
+	if header == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(header); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if when, err := time.Parse(time.RFC1123, header); err == nil {
+		return time.Until(when)
+	}
+	return 0
+}
+
+// isTemporary reports whether err is a transient failure worth retrying:
+// a retryable HTTP status (httpStatusError), a net.Error with
+// Timeout()/Temporary() set, an EOF mid-body, or a connection reset,
+// refused or broken pipe.
+func isTemporary(err error) bool {
+	var statusErr *httpStatusError
+	if errors.As(err, &statusErr) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		if netErr.Timeout() {
+			return true
+		}
+		//lint:ignore SA1019 Temporary is deprecated but still the most portable signal here.
+		if t, ok := netErr.(interface{ Temporary() bool }); ok && t.Temporary() {
+			return true
+		}
+	}
+
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	msg := err.Error()
+	if strings.Contains(msg, "connection reset by peer") || strings.Contains(msg, "connection refused") || strings.Contains(msg, "broken pipe") {
+		return true
+	}
+
+	return false
+}
+
+// withRetry calls fn, retrying up to cfg.MaxRetries times with jittered
+// exponential backoff when the failure is classified as temporary by
+// isTemporary. Non-temporary errors (e.g. 4xx auth/validation failures)
+// are returned immediately.
+func withRetry(fn func() ([]byte, error)) ([]byte, error) {
+	cfg := DefaultRetryConfig
+
+	var lastErr error
+	for attempt := 0; attempt <= cfg.MaxRetries; attempt++ {
+		resp, err := fn()
+		if err == nil {
+			return resp, nil
+		}
+
+		lastErr = err
+		if !isTemporary(err) || attempt == cfg.MaxRetries {
+			return nil, err
+		}
+
+		delay := backoffDelay(cfg, attempt)
+		if statusErr, ok := err.(*httpStatusError); ok && statusErr.retryAfter > 0 {
+			delay = statusErr.retryAfter
+		}
+
+		log.WithError(err).Warnf("temporary error on attempt %d/%d, retrying in %s", attempt+1, cfg.MaxRetries+1, delay)
+		time.Sleep(delay)
+	}
+
+	return nil, lastErr
+}
+
+// backoffDelay returns a jittered exponential backoff delay for the given
+// attempt number (0-indexed), capped at cfg.MaxDelay.
+func backoffDelay(cfg RetryConfig, attempt int) time.Duration {
+	delay := cfg.BaseDelay * time.Duration(1<<uint(attempt))
+	if delay > cfg.MaxDelay {
+		delay = cfg.MaxDelay
+	}
+
+	jitter := time.Duration(rand.Int63n(int64(delay)/2 + 1))
+	return delay/2 + jitter
+}
@@ -0,0 +1,80 @@
+package internal
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBackoffDelay(t *testing.T) {
+	cfg := RetryConfig{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+
+	t.Run("grows with attempt but stays within [delay/2, delay]", func(t *testing.T) {
+		for attempt := 0; attempt < 5; attempt++ {
+			expected := cfg.BaseDelay * time.Duration(1<<uint(attempt))
+			if expected > cfg.MaxDelay {
+				expected = cfg.MaxDelay
+			}
+
+			got := backoffDelay(cfg, attempt)
+			if got < expected/2 || got > expected {
+				t.Fatalf("attempt %d: expected delay in [%s, %s], got %s", attempt, expected/2, expected, got)
+			}
+		}
+	})
+
+	t.Run("caps at MaxDelay", func(t *testing.T) {
+		got := backoffDelay(cfg, 10)
+		if got > cfg.MaxDelay {
+			t.Fatalf("expected delay capped at %s, got %s", cfg.MaxDelay, got)
+		}
+	})
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	t.Run("empty header", func(t *testing.T) {
+		if got := parseRetryAfter(""); got != 0 {
+			t.Fatalf("expected 0, got %s", got)
+		}
+	})
+
+	t.Run("seconds", func(t *testing.T) {
+		if got := parseRetryAfter("5"); got != 5*time.Second {
+			t.Fatalf("expected 5s, got %s", got)
+		}
+	})
+
+	t.Run("http-date", func(t *testing.T) {
+		when := time.Now().Add(10 * time.Second).UTC()
+		got := parseRetryAfter(when.Format(time.RFC1123))
+		if got <= 0 || got > 10*time.Second {
+			t.Fatalf("expected a positive delay close to 10s, got %s", got)
+		}
+	})
+
+	t.Run("garbage", func(t *testing.T) {
+		if got := parseRetryAfter("not-a-date"); got != 0 {
+			t.Fatalf("expected 0, got %s", got)
+		}
+	})
+}
+
+func TestIsTemporary(t *testing.T) {
+	t.Run("httpStatusError is temporary", func(t *testing.T) {
+		if !isTemporary(&httpStatusError{statusCode: 503}) {
+			t.Fatal("expected httpStatusError to be temporary")
+		}
+	})
+
+	t.Run("connection reset is temporary", func(t *testing.T) {
+		if !isTemporary(errors.New("read: connection reset by peer")) {
+			t.Fatal("expected connection reset to be temporary")
+		}
+	})
+
+	t.Run("unrelated error is not temporary", func(t *testing.T) {
+		if isTemporary(errors.New("invalid group id")) {
+			t.Fatal("expected unrelated error to not be temporary")
+		}
+	})
+}
@@ -0,0 +1,44 @@
+package internal
+
+import (
+	"context"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Sink publishes events emitted by the collector to some downstream
+// system (stdout, Elasticsearch, a webhook, ...).
+type Sink interface {
+	Emit(ctx context.Context, event Event) error
+}
+
+// sinkSet fans an event out to every configured Sink. A failure on one
+// sink is logged but doesn't stop the others from receiving the event.
+type sinkSet []Sink
+
+func (s sinkSet) emit(ctx context.Context, event Event) {
+	for _, sink := range s {
+		if err := sink.Emit(ctx, event); err != nil {
+			log.WithError(err).Error("sink failed to emit event")
+		}
+	}
+}
+
+// flushableSink is implemented by sinks that batch events (e.g.
+// ElasticsearchSink) and need an explicit flush once a collection pass ends,
+// rather than waiting for their batch size to be reached.
+type flushableSink interface {
+	Flush(ctx context.Context) error
+}
+
+func (s sinkSet) flush(ctx context.Context) {
+	for _, sink := range s {
+		flushable, ok := sink.(flushableSink)
+		if !ok {
+			continue
+		}
+		if err := flushable.Flush(ctx); err != nil {
+			log.WithError(err).Error("sink failed to flush")
+		}
+	}
+}
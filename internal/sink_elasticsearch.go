@@ -0,0 +1,139 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Rollover selects how often ElasticsearchSink rolls over to a new index.
+type Rollover string
+
+const (
+	// RolloverDaily rolls over to a new index every day.
+	RolloverDaily Rollover = "daily"
+	// RolloverMonthly rolls over to a new index every month.
+	RolloverMonthly Rollover = "monthly"
+)
+
+// ElasticsearchSink batches events and bulk-indexes them into Elasticsearch,
+// rolling the index name over daily or monthly.
+type ElasticsearchSink struct {
+	url         string
+	indexPrefix string
+	rollover    Rollover
+	batchSize   int
+	client      *http.Client
+
+	mu     sync.Mutex
+	buffer []Event
+}
+
+// NewElasticsearchSink builds an ElasticsearchSink posting bulk requests to
+// url (the Elasticsearch base URL), indexing into indices named
+// indexPrefix-<rollover period>, flushing once batchSize events have
+// accumulated.
+func NewElasticsearchSink(url string, indexPrefix string, rollover Rollover, batchSize int) *ElasticsearchSink {
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+
+	return &ElasticsearchSink{
+		url:         url,
+		indexPrefix: indexPrefix,
+		rollover:    rollover,
+		batchSize:   batchSize,
+		client:      &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Emit implements Sink. It buffers the event and flushes the batch once
+// batchSize has been reached.
+func (s *ElasticsearchSink) Emit(ctx context.Context, event Event) error {
+	s.mu.Lock()
+	s.buffer = append(s.buffer, event)
+	shouldFlush := len(s.buffer) >= s.batchSize
+	s.mu.Unlock()
+
+	if !shouldFlush {
+		return nil
+	}
+	return s.Flush(ctx)
+}
+
+// Flush sends any buffered events to Elasticsearch's _bulk endpoint
+// immediately, regardless of batch size.
+func (s *ElasticsearchSink) Flush(ctx context.Context) error {
+	s.mu.Lock()
+	batch := s.buffer
+	s.buffer = nil
+	s.mu.Unlock()
+
+	if len(batch) == 0 {
+		return nil
+	}
+
+	var body bytes.Buffer
+	for _, event := range batch {
+		meta, err := json.Marshal(map[string]map[string]string{
+			"index": {"_index": s.indexName(time.Now())},
+		})
+		if err != nil {
+			return err
+		}
+		doc, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+
+		body.Write(meta)
+		body.WriteByte('\n')
+		body.Write(doc)
+		body.WriteByte('\n')
+	}
+
+	_, err := withRetry(func() ([]byte, error) {
+		return s.bulk(ctx, body.Bytes())
+	})
+	return err
+}
+
+func (s *ElasticsearchSink) bulk(ctx context.Context, body []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", s.url+"/_bulk", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("unable to make request: %s", err)
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if isRetryableStatus(resp.StatusCode) {
+		return nil, &httpStatusError{statusCode: resp.StatusCode, retryAfter: parseRetryAfter(resp.Header.Get("Retry-After"))}
+	}
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("elasticsearch bulk request returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	return respBody, nil
+}
+
+func (s *ElasticsearchSink) indexName(t time.Time) string {
+	if s.rollover == RolloverMonthly {
+		return fmt.Sprintf("%s-%s", s.indexPrefix, t.Format("2006.01"))
+	}
+	return fmt.Sprintf("%s-%s", s.indexPrefix, t.Format("2006.01.02"))
+}
@@ -0,0 +1,28 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// StdoutSink writes each event to stdout as a JSON log line. It's the
+// default sink, preserving the tool's original stdout-logging behavior.
+type StdoutSink struct{}
+
+// NewStdoutSink builds a StdoutSink.
+func NewStdoutSink() *StdoutSink {
+	return &StdoutSink{}
+}
+
+// Emit implements Sink.
+func (s *StdoutSink) Emit(ctx context.Context, event Event) error {
+	raw, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	log.Info(string(raw))
+	return nil
+}
@@ -0,0 +1,67 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// WebhookSink POSTs each event as JSON to a configured URL, retrying
+// transient failures the same way the Atlas API calls do.
+type WebhookSink struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookSink builds a WebhookSink that posts to url.
+func NewWebhookSink(url string) *WebhookSink {
+	return &WebhookSink{
+		url:    url,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Emit implements Sink.
+func (s *WebhookSink) Emit(ctx context.Context, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	_, err = withRetry(func() ([]byte, error) {
+		return s.post(ctx, body)
+	})
+	return err
+}
+
+func (s *WebhookSink) post(ctx context.Context, body []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", s.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("unable to make request: %s", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if isRetryableStatus(resp.StatusCode) {
+		return nil, &httpStatusError{statusCode: resp.StatusCode, retryAfter: parseRetryAfter(resp.Header.Get("Retry-After"))}
+	}
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("webhook returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	return respBody, nil
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Whyeasy/atlasdb-slow-queries/internal"
+)
+
+// envOrDefault returns the value of the environment variable key, or def if
+// it's unset, so operators can configure the tool via env vars in addition
+// to flags.
+func envOrDefault(key string, def string) string {
+	if v, ok := os.LookupEnv(key); ok {
+		return v
+	}
+	return def
+}
+
+func envOrDefaultInt(key string, def int) int {
+	v, ok := os.LookupEnv(key)
+	if !ok {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return def
+	}
+	return n
+}
+
+func main() {
+	groupID := flag.String("group-id", "", "Atlas project (group) ID")
+	publicKey := flag.String("public-key", "", "Atlas API public key")
+	privateKey := flag.String("private-key", "", "Atlas API private key")
+	since := flag.Int("since", 1, "Number of hours to look back for slow queries on startup")
+	listen := flag.String("listen", "", "Address to expose Prometheus metrics on, e.g. :9117. When empty, runs once and exits")
+	pollInterval := flag.Duration("poll-interval", time.Minute, "How often to poll Atlas when --listen is set")
+	stateFile := flag.String("state-file", "", "Path to persist the per-process/namespace cursor across restarts")
+	includeSecondaries := flag.Bool("include-secondaries", false, "Collect from secondaries and shard members too, not just primaries")
+	replicaSet := flag.String("replica-set", "", "Restrict collection to this replica set name")
+	shard := flag.String("shard", "", "Restrict collection to this shard name")
+	concurrency := flag.Int("concurrency", 5, "Maximum number of processes to collect from concurrently")
+	maxRetries := flag.Int("max-retries", internal.DefaultRetryConfig.MaxRetries, "Maximum number of retries for a transient Atlas API failure")
+	retryBaseDelay := flag.Duration("retry-base-delay", internal.DefaultRetryConfig.BaseDelay, "Base delay before the first retry")
+	retryMaxDelay := flag.Duration("retry-max-delay", internal.DefaultRetryConfig.MaxDelay, "Maximum delay between retries")
+
+	sinks := flag.String("sinks", envOrDefault("ATLAS_SINKS", "stdout"), "Comma-separated list of sinks to publish events to: stdout, webhook, elasticsearch")
+	webhookURL := flag.String("webhook-url", envOrDefault("ATLAS_WEBHOOK_URL", ""), "URL the webhook sink POSTs each event to")
+	esURL := flag.String("es-url", envOrDefault("ATLAS_ES_URL", ""), "Base URL of the Elasticsearch cluster the elasticsearch sink indexes into")
+	esIndex := flag.String("es-index", envOrDefault("ATLAS_ES_INDEX", "atlas-performance-advisor"), "Index name prefix for the elasticsearch sink")
+	esRollover := flag.String("es-rollover", envOrDefault("ATLAS_ES_ROLLOVER", "daily"), "Index rollover period for the elasticsearch sink: daily or monthly")
+	esBatchSize := flag.Int("es-batch-size", envOrDefaultInt("ATLAS_ES_BATCH_SIZE", 100), "Number of events the elasticsearch sink batches before flushing")
+	flag.Parse()
+
+	if *groupID == "" || *publicKey == "" || *privateKey == "" {
+		log.Fatal("group-id, public-key and private-key are required")
+	}
+
+	internal.SetRetryConfig(internal.RetryConfig{
+		MaxRetries: *maxRetries,
+		BaseDelay:  *retryBaseDelay,
+		MaxDelay:   *retryMaxDelay,
+	})
+
+	configuredSinks, err := buildSinks(*sinks, *webhookURL, *esURL, *esIndex, *esRollover, *esBatchSize)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	cfg := internal.Config{
+		GroupID:            *groupID,
+		PublicKey:          *publicKey,
+		PrivateKey:         *privateKey,
+		Since:              *since,
+		Listen:             *listen,
+		IncludeSecondaries: *includeSecondaries,
+		ReplicaSet:         *replicaSet,
+		Shard:              *shard,
+		Concurrency:        *concurrency,
+		PollInterval:       *pollInterval,
+		StateFile:          *stateFile,
+		Sinks:              configuredSinks,
+	}
+
+	if *listen == "" {
+		internal.GetData(cfg)
+		return
+	}
+
+	if err := internal.NewCollector(cfg).Serve(context.Background()); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// buildSinks turns the --sinks flag and its per-sink configuration into the
+// Sink implementations the collector should publish events to.
+func buildSinks(sinksFlag, webhookURL, esURL, esIndex, esRollover string, esBatchSize int) ([]internal.Sink, error) {
+	var sinks []internal.Sink
+
+	for _, name := range strings.Split(sinksFlag, ",") {
+		switch strings.TrimSpace(name) {
+		case "stdout":
+			sinks = append(sinks, internal.NewStdoutSink())
+		case "webhook":
+			if webhookURL == "" {
+				return nil, fmt.Errorf("--webhook-url is required when \"webhook\" is in --sinks")
+			}
+			sinks = append(sinks, internal.NewWebhookSink(webhookURL))
+		case "elasticsearch":
+			if esURL == "" {
+				return nil, fmt.Errorf("--es-url is required when \"elasticsearch\" is in --sinks")
+			}
+			rollover := internal.RolloverDaily
+			if esRollover == "monthly" {
+				rollover = internal.RolloverMonthly
+			}
+			sinks = append(sinks, internal.NewElasticsearchSink(esURL, esIndex, rollover, esBatchSize))
+		case "":
+			// allow trailing commas / empty --sinks without erroring
+		default:
+			return nil, fmt.Errorf("unknown sink %q", name)
+		}
+	}
+
+	return sinks, nil
+}